@@ -0,0 +1,51 @@
+// Package auth holds the identity types shared by every auth transport.
+package auth
+
+import (
+	"golang.org/x/net/context"
+)
+
+// Principal is the authenticated identity attached to a request's context.
+type Principal struct {
+	// Username identifies the caller for Basic Auth and opaque bearer
+	// tokens. Empty for OIDC (JWT) bearer tokens; use Subject instead.
+	Username string
+
+	// Subject is the "sub" claim of a validated JWT. Empty for Basic Auth.
+	Subject string
+
+	// Scopes are the space-delimited "scope" claim, split on whitespace.
+	Scopes []string
+
+	// Groups are the "groups" claim, when the issuer provides one.
+	Groups []string
+
+	// Authenticated is true once the credential (password or token
+	// signature/exp/iss/aud) has been verified.
+	Authenticated bool
+}
+
+// HasScope reports whether the principal was granted the given scope.
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey int
+
+const principalKey contextKey = 0
+
+// NewContext returns a copy of ctx carrying p as the request's principal.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey, p)
+}
+
+// FromContext extracts the Principal stored by NewContext, if any.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey).(*Principal)
+	return p, ok
+}
@@ -0,0 +1,186 @@
+// Package oidc validates OAuth2 / OIDC bearer tokens against an issuer's JWKS.
+package oidc
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/mwhooker/gokit-test/auth"
+)
+
+// Verifier validates bearer tokens issued by a single OIDC issuer.
+type Verifier struct {
+	IssuerURL string
+	Audience  string
+
+	// JWKSURL overrides the well-known JWKS location for the issuer.
+	// Defaults to IssuerURL + "/.well-known/jwks.json" when empty.
+	JWKSURL string
+
+	// RefreshInterval controls how often the JWKS is re-fetched.
+	// Defaults to 10 minutes.
+	RefreshInterval time.Duration
+
+	httpClient *http.Client
+
+	mu      sync.RWMutex
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time
+}
+
+// NewVerifier constructs a Verifier and performs an initial JWKS fetch.
+func NewVerifier(issuerURL, audience string) (*Verifier, error) {
+	v := &Verifier{
+		IssuerURL:       issuerURL,
+		Audience:        audience,
+		RefreshInterval: 10 * time.Minute,
+		httpClient:      http.DefaultClient,
+	}
+	if err := v.refreshKeys(); err != nil {
+		return nil, fmt.Errorf("oidc: fetching initial JWKS: %v", err)
+	}
+	return v, nil
+}
+
+type jwks struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func (v *Verifier) jwksURL() string {
+	if v.JWKSURL != "" {
+		return v.JWKSURL
+	}
+	return strings.TrimRight(v.IssuerURL, "/") + "/.well-known/jwks.json"
+}
+
+func (v *Verifier) refreshKeys() error {
+	resp, err := v.httpClient.Get(v.jwksURL())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: unexpected status fetching JWKS: %s", resp.Status)
+	}
+
+	var set jwks
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("oidc: decoding JWKS: %v", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		pub, err := parseRSAPublicKey(k)
+		if err != nil {
+			return fmt.Errorf("oidc: parsing key %q: %v", k.Kid, err)
+		}
+		keys[k.Kid] = pub
+	}
+
+	v.mu.Lock()
+	v.keys = keys
+	v.fetched = time.Now()
+	v.mu.Unlock()
+	return nil
+}
+
+func (v *Verifier) keyFor(kid string) (*rsa.PublicKey, error) {
+	v.mu.RLock()
+	stale := time.Since(v.fetched) > v.RefreshInterval
+	key, ok := v.keys[kid]
+	v.mu.RUnlock()
+
+	if ok && !stale {
+		return key, nil
+	}
+	if err := v.refreshKeys(); err != nil {
+		if ok {
+			// Serve the stale key rather than fail a valid token just
+			// because the issuer is momentarily unreachable.
+			return key, nil
+		}
+		return nil, err
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	key, ok = v.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("oidc: unknown key id %q", kid)
+	}
+	return key, nil
+}
+
+// Verify validates the signature, exp, iss and aud of tokenString and
+// returns the resulting Principal with its claims populated.
+func (v *Verifier) Verify(tokenString string) (*auth.Principal, error) {
+	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("oidc: unexpected signing method %v", t.Header["alg"])
+		}
+		kid, _ := t.Header["kid"].(string)
+		return v.keyFor(kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("oidc: invalid token: %v", err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("oidc: invalid token claims")
+	}
+
+	if !claims.VerifyIssuer(v.IssuerURL, true) {
+		return nil, fmt.Errorf("oidc: unexpected issuer %v", claims["iss"])
+	}
+	if !claims.VerifyAudience(v.Audience, true) {
+		return nil, fmt.Errorf("oidc: unexpected audience %v", claims["aud"])
+	}
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, fmt.Errorf("oidc: token missing or expired exp claim")
+	}
+
+	p := &auth.Principal{
+		Authenticated: true,
+	}
+	if sub, ok := claims["sub"].(string); ok {
+		p.Subject = sub
+	}
+	if scope, ok := claims["scope"].(string); ok {
+		p.Scopes = strings.Fields(scope)
+	}
+	if groups, ok := claims["groups"].([]interface{}); ok {
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				p.Groups = append(p.Groups, s)
+			}
+		}
+	}
+
+	return p, nil
+}
+
+// BearerToken extracts the token from an "Authorization: Bearer <jwt>"
+// header value. ok is false if the header isn't a bearer credential.
+func BearerToken(authorizationHeader string) (token string, ok bool) {
+	const prefix = "Bearer "
+	if !strings.HasPrefix(authorizationHeader, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(authorizationHeader, prefix), true
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mwhooker/gokit-test/users"
+)
+
+func TestCreateUserHandler(t *testing.T) {
+	store := users.NewMemoryStore()
+	handler := createUserHandler(store)
+
+	req := httptest.NewRequest("POST", "/users", bytes.NewBufferString(`{"username":"alice","password":"hunter2"}`))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if err := store.Verify("alice", "hunter2"); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+}
+
+func TestCreateUserHandlerDuplicate(t *testing.T) {
+	store := users.NewMemoryStore()
+	if err := store.Create("alice", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	handler := createUserHandler(store)
+
+	req := httptest.NewRequest("POST", "/users", bytes.NewBufferString(`{"username":"alice","password":"hunter2"}`))
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusConflict)
+	}
+}
+
+func TestIssueTokenHandler(t *testing.T) {
+	store := users.NewMemoryStore()
+	if err := store.Create("alice", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	handler := issueTokenHandler(store)
+
+	req := httptest.NewRequest("POST", "/users/tokens", nil)
+	req.SetBasicAuth("alice", "hunter2")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var resp struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if resp.Token == "" {
+		t.Fatal("expected a non-empty token")
+	}
+	if _, err := store.VerifyToken(resp.Token); err != nil {
+		t.Fatalf("VerifyToken: %v", err)
+	}
+}
+
+func TestIssueTokenHandlerBadCredentials(t *testing.T) {
+	store := users.NewMemoryStore()
+	if err := store.Create("alice", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	handler := issueTokenHandler(store)
+
+	req := httptest.NewRequest("POST", "/users/tokens", nil)
+	req.SetBasicAuth("alice", "wrong")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/metrics"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/net/context"
+)
+
+// InstrumentingMiddleware records a count and latency observation per
+// call, labeled by method and auth outcome (authenticated/unauthenticated/
+// unauthorized/error).
+func InstrumentingMiddleware(requestCount metrics.Counter, requestLatency metrics.Histogram) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			begin := time.Now()
+			response, err := next(ctx, request)
+
+			outcome := authOutcome(err)
+			requestCount.With("method", "add", "outcome", outcome).Add(1)
+			requestLatency.With("method", "add", "outcome", outcome).Observe(time.Since(begin).Seconds())
+
+			return response, err
+		}
+	}
+}
+
+// ready flips to false once a shutdown signal arrives.
+var ready int32 = 1
+
+func setNotReady() {
+	atomic.StoreInt32(&ready, 0)
+}
+
+func isReady() bool {
+	return atomic.LoadInt32(&ready) != 0
+}
+
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	if !isReady() {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+func debugMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", healthzHandler)
+	mux.HandleFunc("/readyz", readyzHandler)
+	return mux
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// multiError aggregates the independent failures of several listeners
+// draining at once.
+type multiError []error
+
+func (m multiError) Error() string {
+	msgs := make([]string, len(m))
+	for i, err := range m {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// shutdown drains debugSrv, jsonSrv and grpcSrv concurrently, each with
+// its own grace window, then cancels cancelRoot. It returns a multiError
+// naming every listener that failed to drain in time, or nil.
+func shutdown(debugSrv, jsonSrv *http.Server, grpcSrv *grpc.Server, cancelRoot context.CancelFunc, grace time.Duration) error {
+	var (
+		mu   sync.Mutex
+		errs multiError
+	)
+	record := func(label string, err error) {
+		if err == nil {
+			return
+		}
+		mu.Lock()
+		errs = append(errs, fmt.Errorf("%s: %v", label, err))
+		mu.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	go func() {
+		defer wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), grace)
+		defer cancel()
+		record("debug listener", debugSrv.Shutdown(ctx))
+	}()
+	go func() {
+		defer wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), grace)
+		defer cancel()
+		record("HTTP/JSON listener", jsonSrv.Shutdown(ctx))
+	}()
+	go func() {
+		defer wg.Done()
+		stopped := make(chan struct{})
+		go func() {
+			grpcSrv.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(grace):
+			grpcSrv.Stop()
+			record("gRPC listener", fmt.Errorf("did not drain within %s", grace))
+		}
+	}()
+	wg.Wait()
+
+	cancelRoot()
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	kitlog "github.com/go-kit/kit/log"
+	"golang.org/x/net/context"
+
+	"github.com/mwhooker/gokit-test/auth"
+)
+
+type loggingContextKey int
+
+const (
+	requestIDKey loggingContextKey = iota
+	remoteAddrKey
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// requestIDBefore stamps the context with the request id and remote addr.
+func requestIDBefore(ctx context.Context, r *http.Request) context.Context {
+	id := r.Header.Get(requestIDHeader)
+	if id == "" {
+		id = newRequestID()
+	}
+	ctx = context.WithValue(ctx, requestIDKey, id)
+	ctx = context.WithValue(ctx, remoteAddrKey, r.RemoteAddr)
+	return ctx
+}
+
+// requestIDAfter echoes the request id back to the client.
+func requestIDAfter(ctx context.Context, w http.ResponseWriter) context.Context {
+	if id, ok := ctx.Value(requestIDKey).(string); ok {
+		w.Header().Set(requestIDHeader, id)
+	}
+	return ctx
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// LoggingMiddleware logs one line per call: request id, remote address,
+// user, auth result, latency and error.
+func LoggingMiddleware(logger kitlog.Logger) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			begin := time.Now()
+			defer func() {
+				keyvals := []interface{}{
+					"request_id", ctx.Value(requestIDKey),
+					"remote_addr", ctx.Value(remoteAddrKey),
+					"user", principalIdentity(ctx),
+					"auth_result", authOutcome(err),
+					"took", time.Since(begin),
+				}
+				if err != nil {
+					keyvals = append(keyvals, "err", err)
+				}
+				logger.Log(keyvals...)
+			}()
+
+			response, err = next(ctx, request)
+			return response, err
+		}
+	}
+}
+
+// principalIdentity returns the Basic Auth username or OIDC subject.
+func principalIdentity(ctx context.Context) string {
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	if p.Username != "" {
+		return p.Username
+	}
+	return p.Subject
+}
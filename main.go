@@ -1,95 +1,190 @@
 package main
 
 import (
+	"encoding/base64"
 	"encoding/json"
-	"errors"
+	"flag"
 	"fmt"
 	stdlog "log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/go-kit/kit/addsvc/reqrep"
 	"github.com/go-kit/kit/endpoint"
 	kitlog "github.com/go-kit/kit/log"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
 	httptransport "github.com/go-kit/kit/transport/http"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/net/context"
-)
+	"google.golang.org/grpc"
 
-// AddRequest is a request for the add method.
-type AddRequest struct {
-	A int64 `json:"a"`
-	B int64 `json:"b"`
-}
+	"github.com/mwhooker/gokit-test/auth"
+	"github.com/mwhooker/gokit-test/auth/oidc"
+	"github.com/mwhooker/gokit-test/pb"
+	"github.com/mwhooker/gokit-test/service"
+	"github.com/mwhooker/gokit-test/users"
+)
 
-// AddResponse is a response to the add method.
-type AddResponse struct {
-	V int64 `json:"v"`
+// authError is an auth rejection from authenticateMW or authorizeMW. Result
+// is a low-cardinality category (for logs and metrics labels); Reason is
+// the human-readable detail.
+type authError struct {
+	Result string
+	Reason string
 }
 
-type Add func(context.Context, int64, int64) int64
+func (e *authError) Error() string { return e.Reason }
 
-func pureAdd(_ context.Context, a, b int64) int64 { return a + b }
+// authorizeMW grants OIDC bearer tokens carrying requiredScope access; an
+// empty requiredScope skips the scope check. Principals that aren't OIDC
+// (Basic Auth, opaque bearer tokens) are left to authenticateMW, which
+// runs next in the chain and owns rejecting the unauthenticated ones.
+func authorizeMW(requiredScope string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			p, ok := auth.FromContext(ctx)
+			if !ok {
+				return nil, &authError{Result: "unauthorized", Reason: "user not authorized"}
+			}
 
-func makeEndpoint(a Add) endpoint.Endpoint {
-	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		select {
-		default:
-		case <-ctx.Done():
-			return nil, endpoint.ErrContextCanceled
-		}
+			if p.Subject != "" {
+				if requiredScope == "" || p.HasScope(requiredScope) {
+					return next(ctx, request)
+				}
+				return nil, &authError{Result: "unauthorized", Reason: fmt.Sprintf("user not authorized: missing scope %q", requiredScope)}
+			}
 
-		addReq, ok := request.(reqrep.AddRequest)
-		if !ok {
-			return nil, endpoint.ErrBadCast
+			return next(ctx, request)
 		}
-
-		v := a(ctx, addReq.A, addReq.B)
-		return reqrep.AddResponse{V: v}, nil
 	}
 }
 
-func authorizeMW(validUser string) endpoint.Middleware {
+func authenticateMW() endpoint.Middleware {
 	return func(next endpoint.Endpoint) endpoint.Endpoint {
 		return func(ctx context.Context, request interface{}) (interface{}, error) {
-			auth := ctx.Value("User").(*BasicAuth)
-			if auth.Username == validUser {
-				return next(ctx, request)
+			p, ok := auth.FromContext(ctx)
+			if !ok || !p.Authenticated {
+				return nil, &authError{Result: "unauthenticated", Reason: "Bad credentials"}
 			}
-			return nil, errors.New("user not authorized")
+			return next(ctx, request)
 		}
 	}
 }
 
-func authenticateMW() endpoint.Middleware {
-	return func(next endpoint.Endpoint) endpoint.Endpoint {
-		return func(ctx context.Context, request interface{}) (interface{}, error) {
-			auth := ctx.Value("User").(*BasicAuth)
-			if auth.Authenticated() {
-				return next(ctx, request)
+// authOutcome classifies a completed call's result for logs and metrics:
+// "unauthenticated" or "unauthorized" for an *authError, "error" for any
+// other failure, "authenticated" otherwise.
+func authOutcome(err error) string {
+	if ae, ok := err.(*authError); ok {
+		return ae.Result
+	}
+	if err != nil {
+		return "error"
+	}
+	return "authenticated"
+}
+
+// resolveAuthorization parses an "Authorization" header (HTTP) or
+// "authorization" metadata value (gRPC) into an auth.Principal: a bearer
+// token is tried as a JWT against verifier, then as an opaque token
+// against store; anything else is parsed as HTTP Basic Auth.
+func resolveAuthorization(header string, verifier *oidc.Verifier, store users.Store) *auth.Principal {
+	if token, ok := oidc.BearerToken(header); ok {
+		if verifier != nil {
+			if p, err := verifier.Verify(token); err == nil {
+				return p
 			}
-			return nil, errors.New("Bad credentials")
 		}
+		if u, err := store.VerifyToken(token); err == nil {
+			return &auth.Principal{Username: u.Username, Authenticated: true}
+		}
+		return &auth.Principal{}
+	}
+
+	u, pw, ok := parseBasicAuth(header)
+	return &auth.Principal{
+		Username:      u,
+		Authenticated: ok && store.Verify(u, pw) == nil,
+	}
+}
+
+// parseBasicAuth is (*http.Request).BasicAuth for a raw header value, for
+// transports (gRPC) that have no *http.Request.
+func parseBasicAuth(header string) (username, password string, ok bool) {
+	const prefix = "Basic "
+	if !strings.HasPrefix(header, prefix) {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(header, prefix))
+	if err != nil {
+		return "", "", false
 	}
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
 }
 
-type BasicAuth struct {
-	Username string
-	Password string
-	Ok       bool
+// authorizeBefore populates the context with an auth.Principal resolved
+// from the request's Authorization header.
+func authorizeBefore(verifier *oidc.Verifier, store users.Store) httptransport.BeforeFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		p := resolveAuthorization(r.Header.Get("Authorization"), verifier, store)
+		return auth.NewContext(ctx, p)
+	}
 }
 
-// authenticated if username == password
-func (ba *BasicAuth) Authenticated() bool {
-	return ba.Ok && ba.Username == ba.Password
+// createUserHandler handles POST /users, registering a new account.
+func createUserHandler(store users.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.Body.Close()
+
+		if err := store.Create(req.Username, req.Password); err != nil {
+			status := http.StatusInternalServerError
+			if err == users.ErrAlreadyExists {
+				status = http.StatusConflict
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}
 }
 
-func authorizeBefore(ctx context.Context, r *http.Request) context.Context {
-	u, p, ok := r.BasicAuth()
-	ba := &BasicAuth{u, p, ok}
+// issueTokenHandler handles POST /users/tokens: a Basic Auth-authenticated
+// caller trades their password for an opaque bearer token.
+func issueTokenHandler(store users.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		u, pw, ok := r.BasicAuth()
+		if !ok || store.Verify(u, pw) != nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="addsvc"`)
+			http.Error(w, "Bad credentials", http.StatusUnauthorized)
+			return
+		}
 
-	return context.WithValue(ctx, "User", ba)
+		token, err := store.IssueToken(u)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(struct {
+			Token string `json:"token"`
+		}{token})
+	}
 }
 
 func makeHTTPBinding(ctx context.Context, e endpoint.Endpoint, before []httptransport.BeforeFunc, after []httptransport.AfterFunc) http.Handler {
@@ -115,6 +210,13 @@ func makeHTTPBinding(ctx context.Context, e endpoint.Endpoint, before []httptran
 }
 
 func main() {
+	oidcIssuer := flag.String("oidc.issuer", "", "OIDC issuer URL to validate Authorization: Bearer tokens against (e.g. a Zitadel or Hydra instance); leave empty to accept only Basic Auth and opaque tokens from /users/tokens")
+	oidcAudience := flag.String("oidc.audience", "addsvc", "expected 'aud' claim on bearer tokens")
+	oidcScope := flag.String("oidc.scope", "add", "scope required of OIDC (JWT) bearer tokens to call /add; opaque tokens are not scope-checked")
+	usersBackend := flag.String("users.backend", "memory", "credential store backend: memory or bolt")
+	usersBoltPath := flag.String("users.bolt.path", "addsvc.db", "path to the BoltDB file when -users.backend=bolt")
+	shutdownGrace := flag.Duration("shutdown.grace", 30*time.Second, "how long to wait for in-flight requests to drain on SIGINT/SIGTERM")
+	flag.Parse()
 
 	var logger kitlog.Logger
 	logger = kitlog.NewLogfmtLogger(os.Stderr)
@@ -123,42 +225,114 @@ func main() {
 	stdlog.SetFlags(0)                                // flags are handled in our logger
 	debugAddr := ":8001"
 	httpAddr := ":8000"
-	root := context.Background()
+	grpcAddr := ":8002"
+	root, cancelRoot := context.WithCancel(context.Background())
+	defer cancelRoot()
+
+	var verifier *oidc.Verifier
+	if *oidcIssuer != "" {
+		v, err := oidc.NewVerifier(*oidcIssuer, *oidcAudience)
+		if err != nil {
+			logger.Log("fatal", err)
+			os.Exit(1)
+		}
+		verifier = v
+	}
+
+	var store users.Store
+	switch *usersBackend {
+	case "memory":
+		store = users.NewMemoryStore()
+	case "bolt":
+		s, err := users.NewBoltStore(*usersBoltPath)
+		if err != nil {
+			logger.Log("fatal", err)
+			os.Exit(1)
+		}
+		store = s
+	default:
+		logger.Log("fatal", fmt.Errorf("unknown users.backend %q", *usersBackend))
+		os.Exit(1)
+	}
 
 	// Our business and operational domain
-	var a Add = pureAdd
+	var a service.Add = service.PureAdd
 	//a = authorize()(a)
 
+	requestCount := kitprometheus.NewCounterFrom(stdprometheus.CounterOpts{
+		Namespace: "addsvc",
+		Subsystem: "add",
+		Name:      "requests_total",
+		Help:      "Number of requests received.",
+	}, []string{"method", "outcome"})
+	requestLatency := kitprometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+		Namespace: "addsvc",
+		Subsystem: "add",
+		Name:      "request_duration_seconds",
+		Help:      "Total duration of requests in seconds.",
+	}, []string{"method", "outcome"})
+
 	// Server domain
 	var e endpoint.Endpoint
-	e = makeEndpoint(a)
+	e = service.MakeEndpoint(a)
 	e = authenticateMW()(e)
-	e = authorizeMW("user")(e)
+	e = authorizeMW(*oidcScope)(e)
+	e = InstrumentingMiddleware(requestCount, requestLatency)(e)
+	e = LoggingMiddleware(logger)(e)
 
-	errc := make(chan error)
-	go func() {
-		errc <- interrupt()
-	}()
-	// Transport: HTTP (debug/instrumentation)
+	before := []httptransport.BeforeFunc{requestIDBefore, authorizeBefore(verifier, store)}
+	after := []httptransport.AfterFunc{requestIDAfter}
+	handler := makeHTTPBinding(root, e, before, after)
+
+	jsonMux := http.NewServeMux()
+	jsonMux.Handle("/add", handler)
+	jsonMux.HandleFunc("/users", createUserHandler(store))
+	jsonMux.HandleFunc("/users/tokens", issueTokenHandler(store))
+
+	debugSrv := &http.Server{Addr: debugAddr, Handler: debugMux()}
+	jsonSrv := &http.Server{Addr: httpAddr, Handler: jsonMux}
+	grpcSrv := grpc.NewServer()
+	pb.RegisterAddServer(grpcSrv, NewGRPCServer(e, verifier, store))
+
+	errc := make(chan error, 3)
 	go func() {
 		logger.Log("addr", debugAddr, "transport", "debug")
-		errc <- http.ListenAndServe(debugAddr, nil)
+		if err := debugSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errc <- err
+		}
 	}()
-	// Transport: HTTP (JSON)
 	go func() {
-		ctx, cancel := context.WithCancel(root)
-		defer cancel()
-		before := []httptransport.BeforeFunc{authorizeBefore}
-		after := []httptransport.AfterFunc{}
-		handler := makeHTTPBinding(ctx, e, before, after)
 		logger.Log("addr", httpAddr, "transport", "HTTP/JSON")
-		errc <- http.ListenAndServe(httpAddr, handler)
+		if err := jsonSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errc <- err
+		}
+	}()
+	go func() {
+		lis, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			errc <- err
+			return
+		}
+		logger.Log("addr", grpcAddr, "transport", "gRPC")
+		if err := grpcSrv.Serve(lis); err != nil {
+			errc <- err
+		}
 	}()
-	logger.Log("fatal", <-errc)
-}
 
-func interrupt() error {
-	c := make(chan os.Signal)
-	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
-	return fmt.Errorf("%s", <-c)
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case s := <-sig:
+		logger.Log("signal", s, "msg", "draining connections")
+		setNotReady()
+		if err := shutdown(debugSrv, jsonSrv, grpcSrv, cancelRoot, *shutdownGrace); err != nil {
+			logger.Log("fatal", err)
+			os.Exit(1)
+		}
+		logger.Log("msg", "drained cleanly")
+	case err := <-errc:
+		logger.Log("fatal", err)
+		os.Exit(1)
+	}
 }
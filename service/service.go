@@ -0,0 +1,33 @@
+// Package service holds addsvc's business logic and go-kit endpoint.
+package service
+
+import (
+	"github.com/go-kit/kit/addsvc/reqrep"
+	"github.com/go-kit/kit/endpoint"
+	"golang.org/x/net/context"
+)
+
+// Add is the business logic addsvc exposes.
+type Add func(ctx context.Context, a, b int64) int64
+
+// PureAdd is the trivial Add implementation: addition with no side effects.
+func PureAdd(_ context.Context, a, b int64) int64 { return a + b }
+
+// MakeEndpoint adapts a to a go-kit endpoint.Endpoint.
+func MakeEndpoint(a Add) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		select {
+		default:
+		case <-ctx.Done():
+			return nil, endpoint.ErrContextCanceled
+		}
+
+		addReq, ok := request.(reqrep.AddRequest)
+		if !ok {
+			return nil, endpoint.ErrBadCast
+		}
+
+		v := a(ctx, addReq.A, addReq.B)
+		return reqrep.AddResponse{V: v}, nil
+	}
+}
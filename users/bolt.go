@@ -0,0 +1,163 @@
+package users
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	usersBucket  = []byte("users")
+	tokensBucket = []byte("tokens")
+)
+
+// boltStore is a Store backed by a BoltDB file, for deployments that need
+// accounts to survive a restart without standing up a separate database.
+type boltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore opens (creating if necessary) a BoltDB-backed Store at path.
+func NewBoltStore(path string) (Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(usersBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(tokensBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &boltStore{db: db}, nil
+}
+
+func (s *boltStore) Create(username, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(usersBucket)
+		if b.Get([]byte(username)) != nil {
+			return ErrAlreadyExists
+		}
+		u := User{Username: username, PasswordHash: hash}
+		return putUser(b, &u)
+	})
+}
+
+func (s *boltStore) Get(username string) (*User, error) {
+	var u *User
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		u, err = getUser(tx.Bucket(usersBucket), username)
+		return err
+	})
+	return u, err
+}
+
+func (s *boltStore) Verify(username, password string) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		u, err := getUser(tx.Bucket(usersBucket), username)
+		if err != nil {
+			return err
+		}
+		if err := bcrypt.CompareHashAndPassword(u.PasswordHash, []byte(password)); err != nil {
+			return ErrBadCredentials
+		}
+		return nil
+	})
+}
+
+func (s *boltStore) Delete(username string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(usersBucket)
+		u, err := getUser(b, username)
+		if err != nil {
+			return err
+		}
+		if u.Token != "" {
+			if err := tx.Bucket(tokensBucket).Delete([]byte(u.Token)); err != nil {
+				return err
+			}
+		}
+		return b.Delete([]byte(username))
+	})
+}
+
+func (s *boltStore) IssueToken(username string) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		ub := tx.Bucket(usersBucket)
+		u, err := getUser(ub, username)
+		if err != nil {
+			return err
+		}
+
+		tb := tx.Bucket(tokensBucket)
+		if u.Token != "" {
+			if err := tb.Delete([]byte(u.Token)); err != nil {
+				return err
+			}
+		}
+		u.Token = token
+		u.TokenIssued = time.Now()
+		if err := putUser(ub, u); err != nil {
+			return err
+		}
+		return tb.Put([]byte(token), []byte(username))
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *boltStore) VerifyToken(token string) (*User, error) {
+	var u *User
+	err := s.db.View(func(tx *bolt.Tx) error {
+		username := tx.Bucket(tokensBucket).Get([]byte(token))
+		if username == nil {
+			return ErrBadCredentials
+		}
+		var err error
+		u, err = getUser(tx.Bucket(usersBucket), string(username))
+		return err
+	})
+	return u, err
+}
+
+func getUser(b *bolt.Bucket, username string) (*User, error) {
+	raw := b.Get([]byte(username))
+	if raw == nil {
+		return nil, ErrNotFound
+	}
+	var u User
+	if err := json.Unmarshal(raw, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func putUser(b *bolt.Bucket, u *User) error {
+	raw, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(u.Username), raw)
+}
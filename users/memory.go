@@ -0,0 +1,115 @@
+package users
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// memoryStore is a Store backed by an in-memory map. It is meant for
+// development and tests; it does not persist across restarts.
+type memoryStore struct {
+	mu     sync.RWMutex
+	users  map[string]*User
+	tokens map[string]string // token -> username
+}
+
+// NewMemoryStore returns a Store that keeps accounts in memory only.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		users:  make(map[string]*User),
+		tokens: make(map[string]string),
+	}
+}
+
+func (s *memoryStore) Create(username, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[username]; ok {
+		return ErrAlreadyExists
+	}
+	s.users[username] = &User{Username: username, PasswordHash: hash}
+	return nil
+}
+
+func (s *memoryStore) Get(username string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.users[username]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	cp := *u
+	return &cp, nil
+}
+
+func (s *memoryStore) Verify(username, password string) error {
+	s.mu.RLock()
+	u, ok := s.users[username]
+	s.mu.RUnlock()
+	if !ok {
+		return ErrNotFound
+	}
+	if err := bcrypt.CompareHashAndPassword(u.PasswordHash, []byte(password)); err != nil {
+		return ErrBadCredentials
+	}
+	return nil
+}
+
+func (s *memoryStore) Delete(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[username]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.users, username)
+	delete(s.tokens, u.Token)
+	return nil
+}
+
+func (s *memoryStore) IssueToken(username string) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[username]
+	if !ok {
+		return "", ErrNotFound
+	}
+	delete(s.tokens, u.Token)
+	u.Token = token
+	u.TokenIssued = time.Now()
+	s.tokens[token] = username
+	return token, nil
+}
+
+func (s *memoryStore) VerifyToken(token string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	username, ok := s.tokens[token]
+	if !ok {
+		return nil, ErrBadCredentials
+	}
+	cp := *s.users[username]
+	return &cp, nil
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
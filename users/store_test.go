@@ -0,0 +1,100 @@
+package users
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// withStores runs test against a fresh memoryStore and a fresh
+// boltStore, as subtests, so both implementations are held to the same
+// contract.
+func withStores(t *testing.T, test func(t *testing.T, store Store)) {
+	boltFile, err := ioutil.TempFile("", "users-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	boltFile.Close()
+	defer os.Remove(boltFile.Name())
+
+	bolt, err := NewBoltStore(boltFile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stores := map[string]Store{
+		"memory": NewMemoryStore(),
+		"bolt":   bolt,
+	}
+	for name, store := range stores {
+		store := store
+		t.Run(name, func(t *testing.T) {
+			test(t, store)
+		})
+	}
+}
+
+func TestStoreCreateAndVerify(t *testing.T) {
+	withStores(t, func(t *testing.T, store Store) {
+		if err := store.Create("alice", "hunter2"); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if err := store.Create("alice", "hunter2"); err != ErrAlreadyExists {
+			t.Fatalf("Create duplicate: got %v, want ErrAlreadyExists", err)
+		}
+		if err := store.Verify("alice", "hunter2"); err != nil {
+			t.Fatalf("Verify correct password: %v", err)
+		}
+		if err := store.Verify("alice", "wrong"); err != ErrBadCredentials {
+			t.Fatalf("Verify wrong password: got %v, want ErrBadCredentials", err)
+		}
+		if err := store.Verify("bob", "hunter2"); err != ErrNotFound {
+			t.Fatalf("Verify unknown user: got %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestStoreTokens(t *testing.T) {
+	withStores(t, func(t *testing.T, store Store) {
+		if err := store.Create("alice", "hunter2"); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+
+		token, err := store.IssueToken("alice")
+		if err != nil {
+			t.Fatalf("IssueToken: %v", err)
+		}
+		if token == "" {
+			t.Fatal("IssueToken returned an empty token")
+		}
+
+		u, err := store.VerifyToken(token)
+		if err != nil {
+			t.Fatalf("VerifyToken: %v", err)
+		}
+		if u.Username != "alice" {
+			t.Fatalf("VerifyToken: got username %q, want %q", u.Username, "alice")
+		}
+
+		if _, err := store.VerifyToken("bogus"); err != ErrBadCredentials {
+			t.Fatalf("VerifyToken bogus: got %v, want ErrBadCredentials", err)
+		}
+	})
+}
+
+func TestStoreDelete(t *testing.T) {
+	withStores(t, func(t *testing.T, store Store) {
+		if err := store.Create("alice", "hunter2"); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if err := store.Delete("alice"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if err := store.Delete("alice"); err != ErrNotFound {
+			t.Fatalf("Delete again: got %v, want ErrNotFound", err)
+		}
+		if _, err := store.Get("alice"); err != ErrNotFound {
+			t.Fatalf("Get after delete: got %v, want ErrNotFound", err)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+// Package users provides a pluggable, bcrypt-backed account store.
+package users
+
+import (
+	"errors"
+	"time"
+)
+
+// User is a single stored account.
+type User struct {
+	Username     string
+	PasswordHash []byte
+	Token        string
+	TokenIssued  time.Time
+}
+
+var (
+	// ErrNotFound is returned when a username has no stored account.
+	ErrNotFound = errors.New("users: not found")
+	// ErrAlreadyExists is returned by Create when the username is taken.
+	ErrAlreadyExists = errors.New("users: already exists")
+	// ErrBadCredentials is returned by Verify and VerifyToken on mismatch.
+	ErrBadCredentials = errors.New("users: bad credentials")
+)
+
+// Store persists accounts. Implementations must be safe for concurrent use.
+type Store interface {
+	// Create registers a new user. Returns ErrAlreadyExists if taken.
+	Create(username, password string) error
+
+	// Get returns the stored user, or ErrNotFound.
+	Get(username string) (*User, error)
+
+	// Verify checks password against the stored hash for username.
+	Verify(username, password string) error
+
+	// Delete removes a user. Returns ErrNotFound if it doesn't exist.
+	Delete(username string) error
+
+	// IssueToken issues a new opaque bearer token for username.
+	IssueToken(username string) (string, error)
+
+	// VerifyToken returns the user token was issued to.
+	VerifyToken(token string) (*User, error)
+}